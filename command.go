@@ -0,0 +1,304 @@
+package hanu
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Handler is the classic, argument-less handler signature.
+type Handler func(ConversationInterface)
+
+// ArgHandler is the handler signature for commands declared with typed
+// arguments: it receives the parsed Args alongside the conversation.
+type ArgHandler func(conv ConversationInterface, args Args)
+
+// ArgType describes how a declared parameter is parsed, validated and
+// rendered in usage strings.
+type ArgType int
+
+const (
+	// ArgString accepts any non-empty token.
+	ArgString ArgType = iota
+	// ArgInt accepts a base-10 integer.
+	ArgInt
+	// ArgBool accepts true/false (and the usual strconv.ParseBool spellings).
+	ArgBool
+	// ArgChoice accepts one of a fixed set of values, given by Arg.Choices.
+	ArgChoice
+	// ArgRest swallows the remainder of the line verbatim. Only valid as
+	// the last declared Arg.
+	ArgRest
+)
+
+// Arg declares a single named command parameter.
+type Arg struct {
+	Name        string
+	Type        ArgType
+	Description string
+	Default     string
+	Optional    bool
+	Choices     []string
+}
+
+// Args holds the parsed values for a matched command's arguments, keyed
+// by Arg.Name.
+type Args map[string]string
+
+// String returns the raw value for name, or "" if it wasn't supplied.
+func (a Args) String(name string) string {
+	return a[name]
+}
+
+// Int parses the value for name as a base-10 integer.
+func (a Args) Int(name string) (int, error) {
+	return strconv.Atoi(a[name])
+}
+
+// Bool parses the value for name the way strconv.ParseBool does,
+// defaulting to false for anything it doesn't recognise.
+func (a Args) Bool(name string) bool {
+	v, _ := strconv.ParseBool(a[name])
+	return v
+}
+
+// CommandInterface defines a command interface
+type CommandInterface interface {
+	Get() *Command
+	Handle(conv ConversationInterface, args Args)
+	Description() string
+	Usage() string
+}
+
+// Command represents a single bot command: its match pattern, declared
+// arguments and the handler that runs once those arguments validate.
+type Command struct {
+	name        string
+	description string
+	examples    []string
+	args        []Arg
+	handler     ArgHandler
+	regex       *regexp.Regexp
+}
+
+// NewCommand creates a new Command with the classic, argument-less
+// Handler signature. It's a compatibility shim for commands that don't
+// need a typed grammar: cmd is used as the match regex as-is and no Args
+// are declared.
+func NewCommand(cmd string, description string, handler Handler) CommandInterface {
+	c := &Command{
+		name:        cmd,
+		description: description,
+		regex:       regexp.MustCompile(cmd),
+		handler: func(conv ConversationInterface, _ Args) {
+			handler(conv)
+		},
+	}
+
+	return c
+}
+
+// NewCommandWithArgs creates a new Command that declares typed
+// parameters. name is the literal word the user types after the prefix
+// (e.g. "deploy"); args describe the grammar that follows it and are
+// rendered into the command's Usage() string and validated against the
+// matched text before handler runs.
+func NewCommandWithArgs(name string, description string, args []Arg, handler ArgHandler) CommandInterface {
+	return &Command{
+		name:        name,
+		description: description,
+		args:        args,
+		regex:       regexp.MustCompile(buildPattern(name, args)),
+		handler:     handler,
+	}
+}
+
+// SetExamples attaches example invocations shown under the command's
+// usage block by sendHelp.
+func (c *Command) SetExamples(examples ...string) {
+	c.examples = examples
+}
+
+// Examples returns the example invocations set via SetExamples, shown
+// under the command's usage block by sendHelp.
+func (c *Command) Examples() []string {
+	return c.examples
+}
+
+// Get returns the Command itself, acting as its own matcher.
+func (c *Command) Get() *Command {
+	return c
+}
+
+// Text returns the literal command name.
+func (c *Command) Text() string {
+	return c.name
+}
+
+// Description returns the human readable summary shown by sendHelp.
+func (c *Command) Description() string {
+	return c.description
+}
+
+// Match runs the command's regex against text, returning the submatches
+// hanu's Conversation is built from. err is non-nil when text doesn't
+// match at all.
+func (c *Command) Match(text string) ([]string, error) {
+	match := c.regex.FindStringSubmatch(text)
+	if match == nil {
+		return nil, errors.New("no match")
+	}
+
+	return match, nil
+}
+
+// ParseArgs validates a successful Match's submatches against the
+// command's declared Args, applying defaults and type checks. It returns
+// a usage error describing the first problem it finds.
+func (c *Command) ParseArgs(match []string) (Args, error) {
+	values := Args{}
+	names := c.regex.SubexpNames()
+
+	for _, arg := range c.args {
+		raw := ""
+		found := false
+
+		for i, n := range names {
+			if n == arg.Name && i < len(match) {
+				raw = match[i]
+				found = raw != ""
+				break
+			}
+		}
+
+		if !found {
+			if arg.Optional || arg.Default != "" {
+				raw = arg.Default
+			} else {
+				return nil, fmt.Errorf("missing required argument <%s>\nUsage: %s", arg.Name, c.Usage())
+			}
+		}
+
+		switch arg.Type {
+		case ArgInt:
+			if raw != "" {
+				if _, err := strconv.Atoi(raw); err != nil {
+					return nil, fmt.Errorf("<%s> must be a number, got %q\nUsage: %s", arg.Name, raw, c.Usage())
+				}
+			}
+		case ArgBool:
+			if raw != "" {
+				if _, err := strconv.ParseBool(raw); err != nil {
+					return nil, fmt.Errorf("<%s> must be true/false, got %q\nUsage: %s", arg.Name, raw, c.Usage())
+				}
+			}
+		case ArgChoice:
+			if raw != "" && !contains(arg.Choices, raw) {
+				return nil, fmt.Errorf("<%s> must be one of %s, got %q\nUsage: %s", arg.Name, strings.Join(arg.Choices, "|"), raw, c.Usage())
+			}
+		}
+
+		values[arg.Name] = raw
+	}
+
+	return values, nil
+}
+
+// Handle calls the Command's handler with the already-validated Args.
+// Callers (searchCommand, via the middleware chain) are responsible for
+// running it on its own goroutine.
+func (c *Command) Handle(conv ConversationInterface, args Args) {
+	c.handler(conv, args)
+}
+
+// Usage renders a one-line usage block for the command, e.g.
+// "deploy <env:staging|prod> [force]".
+func (c *Command) Usage() string {
+	parts := []string{c.name}
+
+	for _, arg := range c.args {
+		parts = append(parts, argUsage(arg))
+	}
+
+	return strings.Join(parts, " ")
+}
+
+func argUsage(arg Arg) string {
+	body := arg.Name
+	if arg.Type == ArgChoice && len(arg.Choices) > 0 {
+		body = arg.Name + ":" + strings.Join(arg.Choices, "|")
+	}
+
+	if arg.Optional || arg.Default != "" {
+		return "[" + body + "]"
+	}
+
+	return "<" + body + ">"
+}
+
+// buildPattern turns name plus a list of declared Args into the regex
+// used to match and capture a message's text. Each Arg becomes a named
+// capture group so ParseArgs can pull values back out by name.
+func buildPattern(name string, args []Arg) string {
+	pattern := "^" + regexp.QuoteMeta(name)
+
+	for i, arg := range args {
+		group := "\\S+"
+		switch arg.Type {
+		case ArgChoice:
+			group = "(?:" + strings.Join(quoteAll(arg.Choices), "|") + ")"
+		case ArgRest:
+			group = ".+"
+		}
+
+		capture := fmt.Sprintf("(?P<%s>%s)", arg.Name, group)
+		if arg.Type == ArgRest && i == len(args)-1 {
+			pattern += "\\s+" + capture + "$"
+			continue
+		}
+
+		if arg.Optional || arg.Default != "" {
+			pattern += "(?:\\s+" + capture + ")?"
+		} else {
+			pattern += "\\s+" + capture
+		}
+	}
+
+	if len(args) == 0 || args[len(args)-1].Type != ArgRest {
+		pattern += "$"
+	}
+
+	return pattern
+}
+
+func quoteAll(values []string) []string {
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		quoted[i] = regexp.QuoteMeta(v)
+	}
+
+	return quoted
+}
+
+func contains(values []string, v string) bool {
+	for _, c := range values {
+		if c == v {
+			return true
+		}
+	}
+
+	return false
+}
+
+// containsAny reports whether any of candidates is present in values.
+func containsAny(values, candidates []string) bool {
+	for _, c := range candidates {
+		if contains(values, c) {
+			return true
+		}
+	}
+
+	return false
+}
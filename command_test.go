@@ -0,0 +1,114 @@
+package hanu
+
+import "testing"
+
+func TestCommandMatch(t *testing.T) {
+	cmd := NewCommandWithArgs("deploy", "", []Arg{
+		{Name: "env", Type: ArgChoice, Choices: []string{"staging", "prod"}},
+		{Name: "force", Type: ArgBool, Optional: true},
+	}, nil)
+
+	tests := []struct {
+		text    string
+		matches bool
+	}{
+		{"deploy staging", true},
+		{"deploy staging true", true},
+		{"deploy qa", false},
+		{"deploy", false},
+		{"redeploy staging", false},
+	}
+
+	for _, tt := range tests {
+		_, err := cmd.Get().Match(tt.text)
+		if matched := err == nil; matched != tt.matches {
+			t.Errorf("Match(%q) matched = %v, want %v", tt.text, matched, tt.matches)
+		}
+	}
+}
+
+func TestCommandParseArgs(t *testing.T) {
+	cmd := NewCommandWithArgs("deploy", "", []Arg{
+		{Name: "env", Type: ArgChoice, Choices: []string{"staging", "prod"}},
+		{Name: "count", Type: ArgInt, Optional: true, Default: "1"},
+	}, nil)
+
+	match, err := cmd.Get().Match("deploy prod 3")
+	if err != nil {
+		t.Fatalf("Match returned error: %v", err)
+	}
+
+	args, err := cmd.Get().ParseArgs(match)
+	if err != nil {
+		t.Fatalf("ParseArgs returned error: %v", err)
+	}
+
+	if args.String("env") != "prod" {
+		t.Errorf("env = %q, want %q", args.String("env"), "prod")
+	}
+
+	count, err := args.Int("count")
+	if err != nil || count != 3 {
+		t.Errorf("count = %v (err %v), want 3", count, err)
+	}
+}
+
+func TestCommandParseArgsDefault(t *testing.T) {
+	cmd := NewCommandWithArgs("deploy", "", []Arg{
+		{Name: "env", Type: ArgChoice, Choices: []string{"staging", "prod"}},
+		{Name: "count", Type: ArgInt, Optional: true, Default: "1"},
+	}, nil)
+
+	match, err := cmd.Get().Match("deploy staging")
+	if err != nil {
+		t.Fatalf("Match returned error: %v", err)
+	}
+
+	args, err := cmd.Get().ParseArgs(match)
+	if err != nil {
+		t.Fatalf("ParseArgs returned error: %v", err)
+	}
+
+	if args.String("count") != "1" {
+		t.Errorf("count = %q, want default %q", args.String("count"), "1")
+	}
+}
+
+func TestCommandMatchMissingRequired(t *testing.T) {
+	cmd := NewCommandWithArgs("deploy", "", []Arg{
+		{Name: "env", Type: ArgChoice, Choices: []string{"staging", "prod"}},
+	}, nil)
+
+	match, err := cmd.Get().Match("deploy")
+	if err == nil {
+		t.Fatalf("Match(%q) unexpectedly succeeded: %v", "deploy", match)
+	}
+}
+
+func TestCommandParseArgsInvalidInt(t *testing.T) {
+	cmd := NewCommandWithArgs("deploy", "", []Arg{
+		{Name: "count", Type: ArgInt},
+	}, nil)
+
+	match, err := cmd.Get().Match("deploy notanumber")
+	if err != nil {
+		t.Fatalf("Match returned error: %v", err)
+	}
+
+	if _, err := cmd.Get().ParseArgs(match); err == nil {
+		t.Error("ParseArgs accepted a non-numeric value for an ArgInt")
+	}
+}
+
+func TestCommandSetExamples(t *testing.T) {
+	cmd := NewCommandWithArgs("deploy", "", []Arg{
+		{Name: "env", Type: ArgChoice, Choices: []string{"staging", "prod"}},
+	}, nil)
+
+	cmd.Get().SetExamples("deploy staging", "deploy prod")
+
+	examples := cmd.Get().Examples()
+	if len(examples) != 2 || examples[0] != "deploy staging" || examples[1] != "deploy prod" {
+		t.Errorf("Examples() = %v, want the examples passed to SetExamples", examples)
+	}
+}
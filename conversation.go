@@ -0,0 +1,165 @@
+package hanu
+
+import "errors"
+
+// ConversationInterface is handed to a Command's handler. It exposes the
+// message that triggered the command plus its regex submatches, and lets
+// the handler reply through whichever Transport received it.
+type ConversationInterface interface {
+	Message() Message
+	Match(i int) string
+	Reply(text string) error
+
+	// ReplyWithBlocks, OpenModal and UpdateModal are only honoured by
+	// Transports that implement Interactive; on any other Transport
+	// they return an error instead of silently doing nothing.
+	ReplyWithBlocks(blocks interface{}) error
+	OpenModal(view interface{}) error
+	UpdateModal(viewID string, view interface{}) error
+}
+
+// Conversation is the default ConversationInterface implementation.
+type Conversation struct {
+	msg       Message
+	match     []string
+	transport Transport
+}
+
+// NewConversation builds a Conversation from a command's regex
+// submatches, the Message that triggered it and the Transport to reply
+// through.
+func NewConversation(match []string, msg Message, transport Transport) ConversationInterface {
+	return &Conversation{msg: msg, match: match, transport: transport}
+}
+
+// Message returns the message that triggered the command.
+func (c *Conversation) Message() Message {
+	return c.msg
+}
+
+// Match returns the i-th regex submatch, or "" if there aren't that many.
+func (c *Conversation) Match(i int) string {
+	if i < 0 || i >= len(c.match) {
+		return ""
+	}
+
+	return c.match[i]
+}
+
+// Reply sends text back to the conversation's channel.
+func (c *Conversation) Reply(text string) error {
+	reply := c.msg
+	reply.SetText(text)
+
+	return c.transport.Send(reply)
+}
+
+// ReplyWithBlocks sends a rich, transport-native message (e.g. Slack
+// attachments/blocks) back to the conversation's channel.
+func (c *Conversation) ReplyWithBlocks(blocks interface{}) error {
+	interactive, ok := c.transport.(Interactive)
+	if !ok {
+		return errors.New("transport does not support interactive replies")
+	}
+
+	return interactive.SendBlocks(c.msg, blocks)
+}
+
+// OpenModal opens a modal in response to the interaction that triggered
+// this conversation.
+func (c *Conversation) OpenModal(view interface{}) error {
+	interactive, ok := c.transport.(Interactive)
+	if !ok {
+		return errors.New("transport does not support modals")
+	}
+
+	return interactive.OpenModal(c.msg.TriggerID, view)
+}
+
+// UpdateModal updates an already-open modal by its view ID.
+func (c *Conversation) UpdateModal(viewID string, view interface{}) error {
+	interactive, ok := c.transport.(Interactive)
+	if !ok {
+		return errors.New("transport does not support modals")
+	}
+
+	return interactive.UpdateModal(viewID, view)
+}
+
+// ListenerConversationInterface is handed to a Listener's handler.
+type ListenerConversationInterface interface {
+	Message() Message
+	Reply(text string) error
+	ReplyWithBlocks(blocks interface{}) error
+	OpenModal(view interface{}) error
+	UpdateModal(viewID string, view interface{}) error
+}
+
+// ListenerConversation is the default ListenerConversationInterface
+// implementation.
+type ListenerConversation struct {
+	msg       Message
+	transport Transport
+}
+
+// NewListenerConversation builds a ListenerConversation from the Message
+// that matched a Listener and the Transport to reply through.
+func NewListenerConversation(msg Message, transport Transport) ListenerConversationInterface {
+	return &ListenerConversation{msg: msg, transport: transport}
+}
+
+// Message returns the message that matched the listener.
+func (c *ListenerConversation) Message() Message {
+	return c.msg
+}
+
+// Reply sends text back to the conversation's channel.
+func (c *ListenerConversation) Reply(text string) error {
+	reply := c.msg
+	reply.SetText(text)
+
+	return c.transport.Send(reply)
+}
+
+// ReplyWithBlocks sends a rich, transport-native message back to the
+// conversation's channel.
+func (c *ListenerConversation) ReplyWithBlocks(blocks interface{}) error {
+	interactive, ok := c.transport.(Interactive)
+	if !ok {
+		return errors.New("transport does not support interactive replies")
+	}
+
+	return interactive.SendBlocks(c.msg, blocks)
+}
+
+// OpenModal opens a modal in response to the interaction that triggered
+// this conversation.
+func (c *ListenerConversation) OpenModal(view interface{}) error {
+	interactive, ok := c.transport.(Interactive)
+	if !ok {
+		return errors.New("transport does not support modals")
+	}
+
+	return interactive.OpenModal(c.msg.TriggerID, view)
+}
+
+// UpdateModal updates an already-open modal by its view ID.
+func (c *ListenerConversation) UpdateModal(viewID string, view interface{}) error {
+	interactive, ok := c.transport.(Interactive)
+	if !ok {
+		return errors.New("transport does not support modals")
+	}
+
+	return interactive.UpdateModal(viewID, view)
+}
+
+// listenerConvAdapter lets a ListenerConversationInterface run through
+// the same Middleware chain as commands use. Listeners have no regex
+// submatches, so Match always returns "".
+type listenerConvAdapter struct {
+	ListenerConversationInterface
+}
+
+func (a listenerConvAdapter) Match(i int) string {
+	return ""
+}
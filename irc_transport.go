@@ -0,0 +1,235 @@
+package hanu
+
+import (
+	"bufio"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// IRCTransport speaks plain IRC: NICK/USER registration, channel JOINs,
+// PRIVMSG in both directions and PING/PONG keepalive.
+type IRCTransport struct {
+	Addr     string
+	Nick     string
+	Channels []string
+	TLS      bool
+
+	conn     net.Conn
+	reader   *bufio.Reader
+	messages chan Message
+	sendLock sync.Mutex
+
+	logger  Logger
+	metrics Metrics
+}
+
+// SetLogger replaces the transport's logger, used for connection
+// lifecycle events (reconnects). Bots usually set this via
+// bot.SetLogger instead of calling it directly.
+func (t *IRCTransport) SetLogger(l Logger) {
+	t.logger = l
+}
+
+// SetMetrics replaces the transport's metrics sink, used to report
+// reconnects and Send errors. Bots usually set this via bot.SetMetrics
+// instead of calling it directly.
+func (t *IRCTransport) SetMetrics(m Metrics) {
+	t.metrics = m
+}
+
+func (t *IRCTransport) log() Logger {
+	if t.logger == nil {
+		return DefaultLogger
+	}
+
+	return t.logger
+}
+
+// NewIRCTransport creates a Transport for an IRC bot. addr is a
+// host:port, nick is the bot's IRC nickname and channels are the
+// channels (with leading "#") the bot should join.
+func NewIRCTransport(addr, nick string, channels []string, useTLS bool) *IRCTransport {
+	return &IRCTransport{
+		Addr:     addr,
+		Nick:     nick,
+		Channels: channels,
+		TLS:      useTLS,
+		messages: make(chan Message),
+	}
+}
+
+// Connect dials the IRC server, registers the bot's nick, joins its
+// channels and starts the read/reconnect loop in the background.
+func (t *IRCTransport) Connect() error {
+	if err := t.dial(); err != nil {
+		return err
+	}
+
+	go t.listen()
+
+	return nil
+}
+
+func (t *IRCTransport) dial() error {
+	var conn net.Conn
+	var err error
+	if t.TLS {
+		conn, err = tls.Dial("tcp", t.Addr, nil)
+	} else {
+		conn, err = net.Dial("tcp", t.Addr)
+	}
+	if err != nil {
+		return fmt.Errorf("Failed to connect to IRC server: %s", err)
+	}
+	t.sendLock.Lock()
+	t.conn = conn
+	t.reader = bufio.NewReader(conn)
+	t.sendLock.Unlock()
+
+	t.writeLine(fmt.Sprintf("NICK %s", t.Nick))
+	t.writeLine(fmt.Sprintf("USER %s 0 * :%s", t.Nick, t.Nick))
+	for _, channel := range t.Channels {
+		t.writeLine(fmt.Sprintf("JOIN %s", channel))
+	}
+
+	return nil
+}
+
+// listen reads PRIVMSGs off the current connection, reconnecting with
+// the same backoff schedule as SlackTransport whenever it drops: the
+// delay grows after each drop or failed dial and resets once a dial
+// succeeds. It never gives up.
+func (t *IRCTransport) listen() {
+	var backoff time.Duration
+
+	for {
+		t.readLines()
+
+		backoff = nextBackoff(backoff)
+		t.log().Infof("IRC connection lost, reconnecting in %s", backoff)
+		time.Sleep(backoff)
+
+		if err := t.dial(); err != nil {
+			t.log().Errorf("IRC reconnect failed: %s", err)
+			continue
+		}
+
+		backoff = 0
+		if t.metrics != nil {
+			t.metrics.IncReconnect()
+		}
+	}
+}
+
+func (t *IRCTransport) readLines() {
+	for {
+		line, err := t.reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+
+		if strings.HasPrefix(line, "PING") {
+			t.writeLine("PONG" + strings.TrimPrefix(line, "PING"))
+			continue
+		}
+
+		if msg, ok := parseIRCPrivmsg(line); ok {
+			t.messages <- msg
+		}
+	}
+}
+
+// parseIRCPrivmsg turns a raw ":nick!user@host PRIVMSG target :text" line
+// into a Message, or reports ok=false for anything else.
+func parseIRCPrivmsg(line string) (Message, bool) {
+	if !strings.HasPrefix(line, ":") {
+		return Message{}, false
+	}
+
+	parts := strings.SplitN(line[1:], " PRIVMSG ", 2)
+	if len(parts) != 2 {
+		return Message{}, false
+	}
+
+	nick := strings.SplitN(parts[0], "!", 2)[0]
+
+	targetAndText := strings.SplitN(parts[1], " :", 2)
+	if len(targetAndText) != 2 {
+		return Message{}, false
+	}
+	target := targetAndText[0]
+
+	return Message{
+		Message: targetAndText[1],
+		UserID:  nick,
+		Channel: target,
+		Direct:  !strings.HasPrefix(target, "#"),
+	}, true
+}
+
+// Receive returns the channel incoming Messages are delivered on.
+func (t *IRCTransport) Receive() <-chan Message {
+	return t.messages
+}
+
+// Send delivers msg as a PRIVMSG to its channel (or, for a direct
+// message, back to its sender).
+func (t *IRCTransport) Send(msg Message) error {
+	target := msg.Channel
+	if msg.Direct {
+		target = msg.UserID
+	}
+
+	return t.writeLine(fmt.Sprintf("PRIVMSG %s :%s", target, msg.Message))
+}
+
+// Ack is a no-op: IRC has no envelope_id acknowledgement concept.
+func (t *IRCTransport) Ack(id string) error {
+	return nil
+}
+
+// Close tears down the IRC connection.
+func (t *IRCTransport) Close() error {
+	if t.conn == nil {
+		return nil
+	}
+
+	return t.conn.Close()
+}
+
+// Self returns the bot's own IRC nickname.
+func (t *IRCTransport) Self() string {
+	return t.Nick
+}
+
+// FormatMention renders userID as a bare nick: IRC has no mention
+// markup of its own.
+func (t *IRCTransport) FormatMention(userID string) string {
+	return userID
+}
+
+// StripMarkup strips a leading "botID: " or "botID, " address out of
+// incoming text.
+func (t *IRCTransport) StripMarkup(text, botID string) string {
+	text = strings.TrimPrefix(text, botID+": ")
+	text = strings.TrimPrefix(text, botID+", ")
+
+	return strings.TrimSpace(text)
+}
+
+func (t *IRCTransport) writeLine(line string) error {
+	t.sendLock.Lock()
+	defer t.sendLock.Unlock()
+
+	_, err := fmt.Fprintf(t.conn, "%s\r\n", line)
+	if err != nil && t.metrics != nil {
+		t.metrics.IncSendError()
+	}
+
+	return err
+}
@@ -0,0 +1,35 @@
+package hanu
+
+import "testing"
+
+func TestParseIRCPrivmsgChannel(t *testing.T) {
+	msg, ok := parseIRCPrivmsg(":alice!u@host PRIVMSG #general :hello there")
+	if !ok {
+		t.Fatal("expected a match")
+	}
+
+	if msg.Message != "hello there" || msg.UserID != "alice" || msg.Channel != "#general" || msg.Direct {
+		t.Errorf("got %+v", msg)
+	}
+}
+
+func TestParseIRCPrivmsgDirect(t *testing.T) {
+	msg, ok := parseIRCPrivmsg(":bob!u@host PRIVMSG hanu :hi")
+	if !ok {
+		t.Fatal("expected a match")
+	}
+
+	if !msg.Direct {
+		t.Errorf("expected Direct=true for a non-channel target, got %+v", msg)
+	}
+}
+
+func TestParseIRCPrivmsgIgnoresOtherCommands(t *testing.T) {
+	if _, ok := parseIRCPrivmsg(":server.example PING :12345"); ok {
+		t.Error("expected no match for a non-PRIVMSG line")
+	}
+
+	if _, ok := parseIRCPrivmsg("PING :12345"); ok {
+		t.Error("expected no match for a line without a leading prefix")
+	}
+}
@@ -6,7 +6,7 @@ type ListenerHandler func(ListenerConversationInterface)
 // CommandInterface defines a command interface
 type ListenerInterface interface {
 	Get() string
-	Handle(conv ConversationInterface)
+	Handle(conv ListenerConversationInterface)
 }
 
 // Command a command
@@ -20,9 +20,10 @@ func (c *Listener) SetHandler(handler ListenerHandler) {
 	c.handler = handler
 }
 
-// Handle calls the Listener's handler
-func (c Listener) Handle(conv ConversationInterface) {
-	go c.handler(conv)
+// Handle calls the Listener's handler. Callers (searchListener, via the
+// middleware chain) are responsible for running it on its own goroutine.
+func (c Listener) Handle(conv ListenerConversationInterface) {
+	c.handler(conv)
 }
 
 // Get returns the regex
@@ -0,0 +1,57 @@
+package hanu
+
+import "log"
+
+// Logger is satisfied by structured logging libraries like zerolog, zap
+// (via its SugaredLogger) or the standard library's slog, so a bot can
+// plug in whatever its ops tooling already expects instead of bare
+// log.Printf output.
+type Logger interface {
+	Debugf(format string, args ...interface{})
+	Infof(format string, args ...interface{})
+	Errorf(format string, args ...interface{})
+}
+
+// DefaultLogger logs through the standard library's log package. It's
+// used by a Bot (and its Transport, if supported) until SetLogger is
+// called, and can be passed explicitly to the built-in middlewares that
+// take a Logger.
+var DefaultLogger Logger = stdLogger{}
+
+type stdLogger struct{}
+
+func (stdLogger) Debugf(format string, args ...interface{}) {
+	log.Printf(format, args...)
+}
+
+func (stdLogger) Infof(format string, args ...interface{}) {
+	log.Printf(format, args...)
+}
+
+func (stdLogger) Errorf(format string, args ...interface{}) {
+	log.Printf(format, args...)
+}
+
+type loggerReceiver interface {
+	SetLogger(l Logger)
+}
+
+// SetLogger replaces the Bot's logger, and the active Transport's too if
+// it supports one (like SlackTransport).
+func (b *Bot) SetLogger(l Logger) {
+	b.logger = l
+
+	if lr, ok := b.Transport.(loggerReceiver); ok {
+		lr.SetLogger(l)
+	}
+}
+
+// log returns the Bot's configured Logger, or DefaultLogger if
+// SetLogger was never called.
+func (b *Bot) log() Logger {
+	if b.logger == nil {
+		return DefaultLogger
+	}
+
+	return b.logger
+}
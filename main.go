@@ -1,103 +1,52 @@
 package hanu
 
 import (
-	"encoding/json"
-	"errors"
 	"fmt"
-	"io/ioutil"
 	"net/http"
-
-	"golang.org/x/net/websocket"
 	"regexp"
-	"log"
+	"time"
 )
 
-type handshakeResponseSelf struct {
-	ID string `json:"id"`
-}
-
-type handshakeResponse struct {
-	Ok    bool                  `json:"ok"`
-	Error string                `json:"error"`
-	URL   string                `json:"url"`
-	Self  handshakeResponseSelf `json:"self"`
-}
-
 // Bot is the main object
 type Bot struct {
-	Socket		*websocket.Conn
-	Token		string
-	ID			string
+	Transport	Transport
 	Commands	[]CommandInterface
 	Listeners	[]ListenerInterface
 	Prefix		string
+
+	middlewares	[]Middleware
+	commandACLs	map[CommandInterface]*commandACL
+	logger		Logger
+	metrics		Metrics
+	roles		RoleResolver
 }
 
-// New creates a new bot
-func New(token string) (*Bot, error) {
-	bot := Bot{
-		Token: token,
+// New creates a new bot talking through transport. transport.Connect is
+// called immediately, so New only returns once the bot is live.
+func New(transport Transport) (*Bot, error) {
+	bot := &Bot{
+		Transport: transport,
 		Prefix: "!",
 	}
 
-	return bot.Handshake()
+	if err := transport.Connect(); err != nil {
+		return nil, err
+	}
+
+	return bot, nil
 }
 
 func (b *Bot) SetPrefix(prefix string) {
 	b.Prefix = prefix
 }
 
-// Handshake connects to the Slack API to get a socket connection
-func (b *Bot) Handshake() (*Bot, error) {
-	// Check for HTTP error on connection
-	res, err := http.Get(fmt.Sprintf("https://slack.com/api/rtm.start?token=%s", b.Token))
-	if err != nil {
-		return nil, errors.New("Failed to connect to Slack RTM API")
-	}
-
-	// Check for HTTP status code
-	if res.StatusCode != 200 {
-		return nil, fmt.Errorf("Failed with HTTP Code: %d", res.StatusCode)
-	}
-
-	// Read response body
-	body, err := ioutil.ReadAll(res.Body)
-	res.Body.Close()
-	if err != nil {
-		return nil, fmt.Errorf("Failed to read body from response")
-	}
-
-	// Parse response
-	var response handshakeResponse
-	err = json.Unmarshal(body, &response)
-	if err != nil {
-		return nil, fmt.Errorf("Failed to unmarshal JSON: %s", body)
-	}
-
-	// Check for Slack error
-	if !response.Ok {
-		return nil, errors.New(response.Error)
-	}
-
-	// Assign Slack user ID
-	b.ID = response.Self.ID
-
-	// Connect to websocket
-	b.Socket, err = websocket.Dial(response.URL, "", "https://api.slack.com/")
-	if err != nil {
-		return nil, errors.New("Failed to connect to Websocket")
-	}
-
-	return b, nil
-}
-
 // Process incoming message
 func (b *Bot) process(message Message) {
-	if message.IsBotMessage(b.Prefix, b.ID) {
-		// Strip @BotName from public message
-		message.StripMention(b.ID)
-		// Strip Slack's link markup
-		message.StripLinkMarkup()
+	botID := b.Transport.Self()
+
+	if message.IsBotMessage(b.Prefix, botID) {
+		// Strip the transport's own mention/link markup
+		message.Message = b.Transport.StripMarkup(message.Message, botID)
 		// Strip the defined command prefix
 		message.StripPrefix(b.Prefix)
 
@@ -117,7 +66,11 @@ func (b *Bot) process(message Message) {
 	b.searchListener(message)
 }
 
-// Search for a command matching the message
+// Search for a command matching the message. Once a command's pattern
+// matches, the requesting user/channel are checked against the
+// command's ACL, then its declared Args are parsed and validated before
+// the handler runs through the middleware chain; an args mismatch sends
+// a usage error to the channel instead of silently doing nothing.
 func (b *Bot) searchCommand(msg Message) bool {
 	var cmd CommandInterface
 	c := false
@@ -126,15 +79,60 @@ func (b *Bot) searchCommand(msg Message) bool {
 		cmd = b.Commands[i]
 
 		match, err := cmd.Get().Match(msg.Text())
-		if err == nil {
-			cmd.Handle(NewConversation(match, msg, b.Socket))
+		if err != nil {
+			continue
+		}
+
+		if !b.commandACLs[cmd].allows(msg, b.rolesFor(msg.User())) {
+			continue
+		}
+
+		args, err := cmd.Get().ParseArgs(match)
+		if err != nil {
+			b.sendUsageError(msg, err)
 			c = true
+			continue
 		}
+
+		conv := NewConversation(match, msg, b.Transport)
+		cmd := cmd
+		handler := b.wrap(func(conv ConversationInterface) {
+			cmd.Handle(conv, args)
+		})
+		name := cmd.Get().Text()
+		go func() {
+			start := time.Now()
+			handler(conv)
+			b.recordInvocation(name, start)
+		}()
+		c = true
+	}
+
+	if !c && b.metrics != nil {
+		b.metrics.IncMatchFailure("command")
 	}
 
 	return c
 }
 
+// recordInvocation reports a completed command/listener invocation to
+// the bot's Metrics, if one has been set via SetMetrics.
+func (b *Bot) recordInvocation(name string, start time.Time) {
+	if b.metrics == nil {
+		return
+	}
+
+	b.metrics.IncInvocation(name)
+	b.metrics.ObserveLatency(name, time.Since(start))
+}
+
+// sendUsageError replies in-channel with why a command's arguments
+// failed to validate.
+func (b *Bot) sendUsageError(msg Message, err error) {
+	msg.SetText(err.Error())
+	b.Transport.Send(msg)
+}
+
 func (b *Bot) searchListener(msg Message) bool {
 	var lst ListenerInterface
 	l := false
@@ -145,12 +143,27 @@ func (b *Bot) searchListener(msg Message) bool {
 		r, _ := regexp.Compile(lst.Get())
 
 		if r.MatchString(msg.Message) {
-			log.Printf("Listener Matched: %v\n", msg.Message)
-			lst.Handle(NewListenerConversation(msg, b.Socket))
+			b.log().Infof("listener matched: %v", msg.Message)
+
+			conv := NewListenerConversation(msg, b.Transport)
+			lst := lst
+			handler := b.wrap(func(ConversationInterface) {
+				lst.Handle(conv)
+			})
+			name := "listener:" + lst.Get()
+			go func() {
+				start := time.Now()
+				handler(listenerConvAdapter{conv})
+				b.recordInvocation(name, start)
+			}()
 			l = true
 		}
 	}
 
+	if !l && b.metrics != nil {
+		b.metrics.IncMatchFailure("listener")
+	}
+
 	return l
 }
 
@@ -162,34 +175,52 @@ func (b *Bot) sendHelp(msg Message) {
 	for i := 0; i < len(b.Commands); i++ {
 		cmd = b.Commands[i]
 
-		help = help + "`" + cmd.Get().Text() + "`"
+		help = help + "`" + cmd.Get().Usage() + "`"
 		if cmd.Description() != "" {
 			help = help + " *–* " + cmd.Description()
 		}
 
+		for _, example := range cmd.Get().Examples() {
+			help = help + "\n  e.g. `" + example + "`"
+		}
+
 		help = help + "\n"
 	}
 
 	if !msg.IsDirectMessage() {
-		help = "<@" + msg.User() + ">: " + help
+		help = b.Transport.FormatMention(msg.User()) + ": " + help
 	}
 
 	msg.SetText(help)
-	websocket.JSON.Send(b.Socket, msg)
+	b.Transport.Send(msg)
 }
 
-// Listen for message on socket
+// Listen dispatches incoming messages from the Transport until it closes
+// its Receive channel (e.g. because it gave up reconnecting).
 func (b *Bot) Listen() {
-	var msg Message
+	for msg := range b.Transport.Receive() {
+		go b.process(msg)
+	}
+}
 
-	for {
-		if websocket.JSON.Receive(b.Socket, &msg) == nil {
-			go b.process(msg)
+// ListenHTTP serves slash commands, interactive components and Events
+// API callbacks over HTTP on addr, for Transports that support it (e.g.
+// Slack's HTTPTransport). Requests are fed into the same
+// Commands/Listeners registry as Listen, via the Transport's Receive
+// channel, so bots can run both modes side by side.
+func (b *Bot) ListenHTTP(addr string) error {
+	server, ok := b.Transport.(HTTPTransport)
+	if !ok {
+		return fmt.Errorf("transport does not support HTTP mode")
+	}
 
-			// Clean up message after processign it
-			msg = Message{}
+	go func() {
+		for msg := range server.Receive() {
+			go b.process(msg)
 		}
-	}
+	}()
+
+	return http.ListenAndServe(addr, server)
 }
 
 // Command adds a new command with custom handler
@@ -197,18 +228,38 @@ func (b *Bot) Command(cmd string, handler Handler) {
 	b.Commands = append(b.Commands, NewCommand(cmd, "", handler))
 }
 
+// CommandWithArgs adds a new command with a declared argument grammar;
+// see NewCommandWithArgs for how name and args are interpreted.
+func (b *Bot) CommandWithArgs(name, description string, args []Arg, handler ArgHandler) {
+	b.Commands = append(b.Commands, NewCommandWithArgs(name, description, args, handler))
+}
+
 // Hear adds a new listener with a custom handler
 func (b *Bot) Hear(regex string, handler ListenerHandler) {
 	b.Listeners = append(b.Listeners, NewListener(regex, handler))
 }
 
-// RegisterCommand registers a Command
-func (b *Bot) RegisterCommand(cmd CommandInterface) {
+// RegisterCommand registers a Command, optionally restricting who may
+// invoke it via WithAllowedUsers/WithAllowedChannels.
+func (b *Bot) RegisterCommand(cmd CommandInterface, opts ...CommandOption) {
 	b.Commands = append(b.Commands, cmd)
+
+	if len(opts) == 0 {
+		return
+	}
+
+	acl := &commandACL{}
+	for _, opt := range opts {
+		opt(acl)
+	}
+
+	if b.commandACLs == nil {
+		b.commandACLs = map[CommandInterface]*commandACL{}
+	}
+	b.commandACLs[cmd] = acl
 }
 
 // RegisterListener registers a Listener
 func (b *Bot) RegisterListener(lst ListenerInterface) {
 	b.Listeners = append(b.Listeners, lst)
 }
-
@@ -0,0 +1,67 @@
+package hanu
+
+import "strings"
+
+// Message is a single incoming or outgoing chat message. It is
+// transport-agnostic: Transports translate their native wire format into
+// a Message on the way in, and back out again on Send.
+type Message struct {
+	Message   string // raw message text
+	UserID    string
+	Channel   string
+	Direct    bool   // true for a 1:1 direct message
+	TriggerID string // set by transports that support opening modals (e.g. Slack)
+
+	// Addressed marks a message as already unambiguously targeting the
+	// bot, bypassing IsBotMessage's prefix/mention check. Transports set
+	// this for requests that can't carry the command prefix, like
+	// Slack's HTTP slash commands and interactive component payloads:
+	// Slack itself is what routed them to the bot, so there's no
+	// "!prefix" for the user to have typed.
+	Addressed bool
+}
+
+// Text returns the message's raw text.
+func (m Message) Text() string {
+	return m.Message
+}
+
+// SetText replaces the message's text, e.g. before sending a reply.
+func (m *Message) SetText(text string) {
+	m.Message = text
+}
+
+// User returns the ID of the user who sent the message.
+func (m Message) User() string {
+	return m.UserID
+}
+
+// IsDirectMessage reports whether the message was sent as a 1:1 DM.
+func (m Message) IsDirectMessage() bool {
+	return m.Direct
+}
+
+// IsBotMessage reports whether this message should be treated as aimed
+// at the bot: a direct message, a message marked Addressed, a message
+// starting with the command prefix, or a message that mentions the
+// bot's own ID.
+func (m Message) IsBotMessage(prefix, botID string) bool {
+	if m.Direct || m.Addressed {
+		return true
+	}
+
+	text := strings.TrimSpace(m.Message)
+	return strings.HasPrefix(text, prefix) || strings.Contains(text, botID)
+}
+
+// StripPrefix removes a leading command prefix (e.g. "!") from the
+// message text.
+func (m *Message) StripPrefix(prefix string) {
+	m.Message = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(m.Message), prefix))
+}
+
+// IsHelpRequest reports whether the (already stripped) message text is
+// asking for the auto-generated help command list.
+func (m Message) IsHelpRequest() bool {
+	return strings.TrimSpace(m.Message) == "help"
+}
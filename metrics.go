@@ -0,0 +1,38 @@
+package hanu
+
+import "time"
+
+// Metrics records what a running bot is doing: invocation counts,
+// handler latency, failed command/listener matches, Transport
+// reconnects and send errors. A ready-made Prometheus-backed
+// implementation is available via NewPrometheusMetrics.
+type Metrics interface {
+	// IncInvocation counts one run of the named command or listener.
+	IncInvocation(name string)
+	// ObserveLatency records how long the named command or listener
+	// took to run.
+	ObserveLatency(name string, d time.Duration)
+	// IncMatchFailure counts one message that didn't match any
+	// registered command or listener, respectively.
+	IncMatchFailure(kind string)
+	// IncReconnect counts one Transport reconnect.
+	IncReconnect()
+	// IncSendError counts one failed Transport.Send.
+	IncSendError()
+}
+
+type metricsReceiver interface {
+	SetMetrics(m Metrics)
+}
+
+// SetMetrics wires m into the bot: every command/listener invocation
+// reports counts and latency into it, unmatched messages report a
+// match failure, and if the active Transport supports metrics (like
+// SlackTransport) it also starts reporting reconnects and send errors.
+func (b *Bot) SetMetrics(m Metrics) {
+	b.metrics = m
+
+	if mr, ok := b.Transport.(metricsReceiver); ok {
+		mr.SetMetrics(m)
+	}
+}
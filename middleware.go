@@ -0,0 +1,265 @@
+package hanu
+
+import (
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Middleware wraps a Handler so cross-cutting concerns (logging, ACLs,
+// rate limiting, panic recovery, metrics) can run around every command
+// and listener invocation without each one having to implement them
+// itself.
+type Middleware func(next Handler) Handler
+
+// Use registers a middleware. Middlewares run in the order they were
+// registered, outermost first, around every command and listener
+// handler.
+func (b *Bot) Use(mw Middleware) {
+	b.middlewares = append(b.middlewares, mw)
+}
+
+// wrap builds the final Handler for a single invocation by threading it
+// through every registered middleware, with an unconditional panic
+// recovery outermost so a handler can never take down Listen's caller,
+// whether or not the bot author also registered Recover via Use.
+func (b *Bot) wrap(final Handler) Handler {
+	h := final
+
+	for i := len(b.middlewares) - 1; i >= 0; i-- {
+		h = b.middlewares[i](h)
+	}
+
+	return b.recover(h)
+}
+
+// recover is the panic safety net every invocation runs under, logging
+// through the bot's current Logger (so it reflects SetLogger even if
+// called after Use) rather than a Logger captured at registration time.
+func (b *Bot) recover(next Handler) Handler {
+	return func(conv ConversationInterface) {
+		defer func() {
+			if r := recover(); r != nil {
+				b.log().Errorf("recovered from panic in handler: %v\n%s", r, debug.Stack())
+			}
+		}()
+
+		next(conv)
+	}
+}
+
+// CommandOption configures per-command access control when passed to
+// RegisterCommand.
+type CommandOption func(*commandACL)
+
+type commandACL struct {
+	allowedUsers    []string
+	allowedChannels []string
+	allowedRoles    []string
+}
+
+// WithAllowedUsers restricts a command to the given user IDs. With no
+// WithAllowedUsers option, a command is open to everyone.
+func WithAllowedUsers(users ...string) CommandOption {
+	return func(acl *commandACL) {
+		acl.allowedUsers = users
+	}
+}
+
+// WithAllowedChannels restricts a command to the given channel IDs. With
+// no WithAllowedChannels option, a command is open in every channel.
+func WithAllowedChannels(channels ...string) CommandOption {
+	return func(acl *commandACL) {
+		acl.allowedChannels = channels
+	}
+}
+
+// WithAllowedRoles restricts a command to users holding at least one of
+// the given roles, as reported by the Bot's RoleResolver (see
+// SetRoleResolver). With no WithAllowedRoles option, a command isn't
+// role-restricted; a bot with no RoleResolver set can't satisfy any
+// WithAllowedRoles option, so such commands become unreachable.
+func WithAllowedRoles(roles ...string) CommandOption {
+	return func(acl *commandACL) {
+		acl.allowedRoles = roles
+	}
+}
+
+// RoleResolver maps a user ID to the roles they hold, so WithAllowedRoles
+// ACLs have something to check against. Wire one in via
+// bot.SetRoleResolver.
+type RoleResolver interface {
+	Roles(userID string) []string
+}
+
+// SetRoleResolver wires r into the bot so WithAllowedRoles ACLs can
+// resolve a message's user to their roles.
+func (b *Bot) SetRoleResolver(r RoleResolver) {
+	b.roles = r
+}
+
+// rolesFor returns userID's roles via the bot's RoleResolver, or nil if
+// none was set via SetRoleResolver.
+func (b *Bot) rolesFor(userID string) []string {
+	if b.roles == nil {
+		return nil
+	}
+
+	return b.roles.Roles(userID)
+}
+
+func (acl *commandACL) allows(msg Message, roles []string) bool {
+	if acl == nil {
+		return true
+	}
+
+	if len(acl.allowedUsers) > 0 && !contains(acl.allowedUsers, msg.User()) {
+		return false
+	}
+
+	if len(acl.allowedChannels) > 0 && !contains(acl.allowedChannels, msg.Channel) {
+		return false
+	}
+
+	if len(acl.allowedRoles) > 0 && !containsAny(acl.allowedRoles, roles) {
+		return false
+	}
+
+	return true
+}
+
+var requestSeq uint64
+
+// nextRequestID returns a short, monotonically increasing ID used to
+// correlate a single command/listener invocation across log lines.
+func nextRequestID() string {
+	return fmt.Sprintf("req-%d", atomic.AddUint64(&requestSeq, 1))
+}
+
+// Recover is a built-in middleware that turns a panicking handler into a
+// logged error instead of letting it propagate. wrap already recovers
+// every invocation unconditionally, so Use(Recover(...)) is only needed
+// to run recovery at a specific point in the chain (e.g. inside a
+// RateLimiter, or logging through a Logger other than the bot's own).
+func Recover(logger Logger) Middleware {
+	return func(next Handler) Handler {
+		return func(conv ConversationInterface) {
+			defer func() {
+				if r := recover(); r != nil {
+					logger.Errorf("recovered from panic in handler: %v\n%s", r, debug.Stack())
+				}
+			}()
+
+			next(conv)
+		}
+	}
+}
+
+// RequestLogger is a built-in middleware that logs the start and end of
+// every handler invocation with a request ID, so log lines from the same
+// invocation (including ones the handler itself emits) can be tied
+// together.
+func RequestLogger(logger Logger) Middleware {
+	return func(next Handler) Handler {
+		return func(conv ConversationInterface) {
+			id := nextRequestID()
+			msg := conv.Message()
+
+			start := time.Now()
+			logger.Infof("[%s] handling %q from %s", id, msg.Text(), msg.User())
+
+			next(conv)
+
+			logger.Infof("[%s] handled in %s", id, time.Since(start))
+		}
+	}
+}
+
+// RateLimiter is a built-in middleware that allows at most limit
+// invocations per user within per, across all commands and listeners it
+// wraps. Requests beyond the limit are dropped with a log line instead
+// of running the handler.
+func RateLimiter(limit int, per time.Duration, logger Logger) Middleware {
+	m, _ := newRateLimiter(limit, per, logger, time.Now)
+	return m
+}
+
+// newRateLimiter is RateLimiter's implementation, with the clock and a
+// windowCount introspection hook broken out so tests can advance time
+// and observe the sweep without sleeping in wall-clock time.
+func newRateLimiter(limit int, per time.Duration, logger Logger, now func() time.Time) (Middleware, func() int) {
+	type window struct {
+		count int
+		reset time.Time
+	}
+
+	var mu sync.Mutex
+	windows := map[string]*window{}
+	var lastSweep time.Time
+
+	middleware := func(next Handler) Handler {
+		return func(conv ConversationInterface) {
+			user := conv.Message().User()
+
+			mu.Lock()
+			n := now()
+
+			// Sweep expired windows at most once per "per" interval,
+			// piggybacking on a regular invocation instead of running a
+			// dedicated ticker, so long-lived bots don't accumulate one
+			// entry per distinct user forever.
+			if n.Sub(lastSweep) >= per {
+				for u, w := range windows {
+					if n.After(w.reset) {
+						delete(windows, u)
+					}
+				}
+				lastSweep = n
+			}
+
+			w, ok := windows[user]
+			if !ok || n.After(w.reset) {
+				w = &window{reset: n.Add(per)}
+				windows[user] = w
+			}
+			w.count++
+			allowed := w.count <= limit
+			mu.Unlock()
+
+			if !allowed {
+				logger.Errorf("rate limit exceeded for user %s", user)
+				return
+			}
+
+			next(conv)
+		}
+	}
+
+	windowCount := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return len(windows)
+	}
+
+	return middleware, windowCount
+}
+
+// CommandMetrics is a built-in middleware that feeds every invocation of
+// name into m. Bots that call bot.SetMetrics don't need this too: it's
+// for attaching Metrics to a single command/listener without wiring it
+// into every one of them.
+func CommandMetrics(m Metrics, name string) Middleware {
+	return func(next Handler) Handler {
+		return func(conv ConversationInterface) {
+			start := time.Now()
+			m.IncInvocation(name)
+
+			next(conv)
+
+			m.ObserveLatency(name, time.Since(start))
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package hanu
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRateLimiterEvictsExpiredWindows(t *testing.T) {
+	now := time.Now()
+	clock := func() time.Time { return now }
+
+	limiter, windowCount := newRateLimiter(5, time.Minute, DefaultLogger, clock)
+	handler := limiter(func(conv ConversationInterface) {})
+
+	for i := 0; i < 100; i++ {
+		user := string(rune('a' + i%26))
+		handler(NewConversation(nil, Message{UserID: user + string(rune(i))}, nil))
+	}
+
+	if got := windowCount(); got == 0 {
+		t.Fatal("expected windows to be populated after invocations")
+	}
+
+	// Advance time past the window and trigger one more invocation: the
+	// sweep should drop every stale entry, leaving only the new user's.
+	now = now.Add(2 * time.Minute)
+	handler(NewConversation(nil, Message{UserID: "fresh"}, nil))
+
+	if got := windowCount(); got != 1 {
+		t.Errorf("windowCount() = %d after sweep, want 1 (stale windows should have been evicted)", got)
+	}
+}
+
+func TestRateLimiterBlocksOverLimit(t *testing.T) {
+	now := time.Now()
+	clock := func() time.Time { return now }
+
+	limiter, _ := newRateLimiter(2, time.Minute, DefaultLogger, clock)
+
+	calls := 0
+	handler := limiter(func(conv ConversationInterface) { calls++ })
+
+	for i := 0; i < 5; i++ {
+		handler(NewConversation(nil, Message{UserID: "u1"}, nil))
+	}
+
+	if calls != 2 {
+		t.Errorf("handler ran %d times, want 2 (limit)", calls)
+	}
+}
@@ -0,0 +1,81 @@
+package hanu
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// PrometheusMetrics is a ready-made Metrics implementation backed by
+// Prometheus client_golang collectors. Register it with a Bot via
+// bot.SetMetrics after constructing it with NewPrometheusMetrics.
+type PrometheusMetrics struct {
+	invocations   *prometheus.CounterVec
+	latency       *prometheus.HistogramVec
+	matchFailures *prometheus.CounterVec
+	reconnects    prometheus.Counter
+	sendErrors    prometheus.Counter
+}
+
+// NewPrometheusMetrics creates a PrometheusMetrics and registers its
+// collectors on reg. namespace prefixes every metric name (e.g.
+// "hanu" produces "hanu_invocations_total"); pass "" to use the
+// client_golang default of no prefix.
+func NewPrometheusMetrics(reg prometheus.Registerer, namespace string) *PrometheusMetrics {
+	m := &PrometheusMetrics{
+		invocations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "invocations_total",
+			Help:      "Total number of command/listener invocations, labeled by name.",
+		}, []string{"name"}),
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "handler_duration_seconds",
+			Help:      "Handler latency in seconds, labeled by command/listener name.",
+		}, []string{"name"}),
+		matchFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "match_failures_total",
+			Help:      "Total number of messages that matched no command or listener, labeled by kind.",
+		}, []string{"kind"}),
+		reconnects: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "transport_reconnects_total",
+			Help:      "Total number of Transport reconnects.",
+		}),
+		sendErrors: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "transport_send_errors_total",
+			Help:      "Total number of failed Transport.Send calls.",
+		}),
+	}
+
+	reg.MustRegister(m.invocations, m.latency, m.matchFailures, m.reconnects, m.sendErrors)
+
+	return m
+}
+
+// IncInvocation implements Metrics.
+func (m *PrometheusMetrics) IncInvocation(name string) {
+	m.invocations.WithLabelValues(name).Inc()
+}
+
+// ObserveLatency implements Metrics.
+func (m *PrometheusMetrics) ObserveLatency(name string, d time.Duration) {
+	m.latency.WithLabelValues(name).Observe(d.Seconds())
+}
+
+// IncMatchFailure implements Metrics.
+func (m *PrometheusMetrics) IncMatchFailure(kind string) {
+	m.matchFailures.WithLabelValues(kind).Inc()
+}
+
+// IncReconnect implements Metrics.
+func (m *PrometheusMetrics) IncReconnect() {
+	m.reconnects.Inc()
+}
+
+// IncSendError implements Metrics.
+func (m *PrometheusMetrics) IncSendError() {
+	m.sendErrors.Inc()
+}
@@ -0,0 +1,385 @@
+package hanu
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+// RocketChatTransport speaks Rocket.Chat's realtime (DDP-over-websocket)
+// API, the same one matterbridge's rocketchat bridge uses: connect,
+// log in with a password, subscribe to the room's message stream, and
+// send replies via the sendMessage method.
+type RocketChatTransport struct {
+	URL      string // e.g. "wss://chat.example.com/websocket"
+	User     string
+	Password string
+
+	socket   *websocket.Conn
+	self     string
+	messages chan Message
+	msgID    int64
+	sendLock sync.Mutex
+
+	// directRooms maps a room ID to whether it's a 1:1 DM, keyed from
+	// subscriptions/get at login and kept current by subscribing to
+	// subscriptions-changed; see room type in dispatchMessage.
+	directRooms map[string]bool
+
+	logger  Logger
+	metrics Metrics
+}
+
+// SetLogger replaces the transport's logger, used for connection
+// lifecycle events (reconnects). Bots usually set this via
+// bot.SetLogger instead of calling it directly.
+func (t *RocketChatTransport) SetLogger(l Logger) {
+	t.logger = l
+}
+
+// SetMetrics replaces the transport's metrics sink, used to report
+// reconnects and Send errors. Bots usually set this via bot.SetMetrics
+// instead of calling it directly.
+func (t *RocketChatTransport) SetMetrics(m Metrics) {
+	t.metrics = m
+}
+
+func (t *RocketChatTransport) log() Logger {
+	if t.logger == nil {
+		return DefaultLogger
+	}
+
+	return t.logger
+}
+
+// NewRocketChatTransport creates a Transport for a Rocket.Chat bot.
+func NewRocketChatTransport(url, user, password string) *RocketChatTransport {
+	return &RocketChatTransport{
+		URL:      url,
+		User:     user,
+		Password: password,
+		messages: make(chan Message),
+	}
+}
+
+// Connect dials the realtime websocket, completes the DDP handshake, logs
+// in, subscribes to room messages and starts the read/reconnect loop in
+// the background.
+func (t *RocketChatTransport) Connect() error {
+	if err := t.dial(); err != nil {
+		return err
+	}
+
+	go t.listen()
+
+	return nil
+}
+
+func (t *RocketChatTransport) dial() error {
+	socket, err := websocket.Dial(t.URL, "", t.URL)
+	if err != nil {
+		return fmt.Errorf("Failed to connect to Rocket.Chat realtime API: %s", err)
+	}
+	t.sendLock.Lock()
+	t.socket = socket
+	t.sendLock.Unlock()
+
+	if err := t.send(map[string]interface{}{"msg": "connect", "version": "1", "support": []string{"1"}}); err != nil {
+		return err
+	}
+
+	if err := t.awaitConnectAck(); err != nil {
+		return err
+	}
+
+	id, err := t.login()
+	if err != nil {
+		return err
+	}
+	t.self = id
+
+	rooms, err := t.fetchSubscriptions()
+	if err != nil {
+		return err
+	}
+	t.directRooms = rooms
+
+	if err := t.send(map[string]interface{}{
+		"msg": "sub", "id": t.nextID(), "name": "stream-room-messages",
+		"params": []interface{}{"__my_messages__", false},
+	}); err != nil {
+		return err
+	}
+
+	if err := t.send(map[string]interface{}{
+		"msg": "sub", "id": t.nextID(), "name": "stream-notify-user",
+		"params": []interface{}{t.self + "/subscriptions-changed", false},
+	}); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// awaitConnectAck reads the "connected"/"failed" envelope DDP sends
+// immediately after a "connect" message, before any method call can
+// succeed. login's own Receive would otherwise decode this envelope
+// instead of the login method's result.
+func (t *RocketChatTransport) awaitConnectAck() error {
+	var ack struct {
+		Msg    string `json:"msg"`
+		Reason string `json:"reason"`
+	}
+	if err := websocket.JSON.Receive(t.socket, &ack); err != nil {
+		return fmt.Errorf("Failed to read connect ack: %s", err)
+	}
+
+	if ack.Msg == "failed" {
+		return fmt.Errorf("Rocket.Chat rejected our DDP version: %s", ack.Reason)
+	}
+
+	return nil
+}
+
+func (t *RocketChatTransport) login() (string, error) {
+	digest := sha256.Sum256([]byte(t.Password))
+	err := t.send(map[string]interface{}{
+		"msg": "method", "method": "login", "id": t.nextID(),
+		"params": []interface{}{map[string]interface{}{
+			"user":     map[string]string{"username": t.User},
+			"password": map[string]string{"digest": hex.EncodeToString(digest[:]), "algorithm": "sha-256"},
+		}},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	// The login method's "result" envelope is the next frame on the
+	// socket now that awaitConnectAck has consumed the "connected" ack.
+	var result struct {
+		Msg    string `json:"msg"`
+		Result struct {
+			ID string `json:"id"`
+		} `json:"result"`
+	}
+	if err := websocket.JSON.Receive(t.socket, &result); err != nil {
+		return "", fmt.Errorf("Failed to read login response: %s", err)
+	}
+
+	return result.Result.ID, nil
+}
+
+// fetchSubscriptions calls subscriptions/get to learn which rooms the
+// bot is currently a member of are 1:1 DMs ("t":"d") versus channels, so
+// dispatchMessage doesn't have to guess from the room ID. Like login,
+// it reads its method result synchronously, which only holds during the
+// handshake in dial before listen's read loop takes over the socket.
+func (t *RocketChatTransport) fetchSubscriptions() (map[string]bool, error) {
+	err := t.send(map[string]interface{}{
+		"msg": "method", "method": "subscriptions/get", "id": t.nextID(),
+		"params": []interface{}{},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Result []struct {
+			RID  string `json:"rid"`
+			Type string `json:"t"`
+		} `json:"result"`
+	}
+	if err := websocket.JSON.Receive(t.socket, &result); err != nil {
+		return nil, fmt.Errorf("Failed to read subscriptions/get response: %s", err)
+	}
+
+	rooms := make(map[string]bool, len(result.Result))
+	for _, sub := range result.Result {
+		rooms[sub.RID] = sub.Type == "d"
+	}
+
+	return rooms, nil
+}
+
+// listen reads DDP envelopes off the current socket, reconnecting with
+// the same backoff schedule as SlackTransport whenever it drops: the
+// delay grows after each drop or failed dial and resets once a dial
+// succeeds. It never gives up.
+func (t *RocketChatTransport) listen() {
+	var backoff time.Duration
+
+	for {
+		t.readEnvelopes()
+
+		backoff = nextBackoff(backoff)
+		t.log().Infof("Rocket.Chat connection lost, reconnecting in %s", backoff)
+		time.Sleep(backoff)
+
+		if err := t.dial(); err != nil {
+			t.log().Errorf("Rocket.Chat reconnect failed: %s", err)
+			continue
+		}
+
+		backoff = 0
+		if t.metrics != nil {
+			t.metrics.IncReconnect()
+		}
+	}
+}
+
+func (t *RocketChatTransport) readEnvelopes() {
+	for {
+		var raw json.RawMessage
+		if err := websocket.JSON.Receive(t.socket, &raw); err != nil {
+			return
+		}
+
+		var envelope struct {
+			Msg        string          `json:"msg"`
+			Collection string          `json:"collection"`
+			Fields     json.RawMessage `json:"fields"`
+		}
+		if err := json.Unmarshal(raw, &envelope); err != nil {
+			continue
+		}
+
+		switch {
+		case envelope.Msg == "ping":
+			t.send(map[string]interface{}{"msg": "pong"})
+		case envelope.Msg == "changed" && envelope.Collection == "stream-notify-user":
+			t.dispatchSubscriptionChange(envelope.Fields)
+		case envelope.Msg == "changed":
+			t.dispatchMessage(envelope.Fields)
+		}
+	}
+}
+
+// dispatchSubscriptionChange keeps directRooms current as the bot is
+// added to or removed from rooms after the initial subscriptions/get
+// snapshot taken in dial.
+func (t *RocketChatTransport) dispatchSubscriptionChange(fields json.RawMessage) {
+	var payload struct {
+		Args []json.RawMessage `json:"args"`
+	}
+	if err := json.Unmarshal(fields, &payload); err != nil || len(payload.Args) < 2 {
+		return
+	}
+
+	var sub struct {
+		RID  string `json:"rid"`
+		Type string `json:"t"`
+	}
+	if err := json.Unmarshal(payload.Args[1], &sub); err != nil || sub.RID == "" {
+		return
+	}
+
+	if t.directRooms == nil {
+		t.directRooms = map[string]bool{}
+	}
+	t.directRooms[sub.RID] = sub.Type == "d"
+}
+
+func (t *RocketChatTransport) dispatchMessage(fields json.RawMessage) {
+	var payload struct {
+		Args []struct {
+			Msg string `json:"msg"`
+			RID string `json:"rid"`
+			U   struct {
+				ID string `json:"_id"`
+			} `json:"u"`
+		} `json:"args"`
+	}
+	if err := json.Unmarshal(fields, &payload); err != nil || len(payload.Args) == 0 {
+		return
+	}
+
+	msg := payload.Args[0]
+	if msg.U.ID == t.self {
+		return
+	}
+
+	t.messages <- Message{
+		Message: msg.Msg,
+		UserID:  msg.U.ID,
+		Channel: msg.RID,
+		// directRooms is seeded from subscriptions/get and kept live by
+		// dispatchSubscriptionChange; a room missing from it (e.g. one
+		// the bot joined moments ago, before its subscriptions-changed
+		// event arrived) is treated as a channel, not a DM.
+		Direct: t.directRooms[msg.RID],
+	}
+}
+
+// Receive returns the channel incoming Messages are delivered on.
+func (t *RocketChatTransport) Receive() <-chan Message {
+	return t.messages
+}
+
+// Send posts msg back to the room it came from via the sendMessage
+// method.
+func (t *RocketChatTransport) Send(msg Message) error {
+	return t.send(map[string]interface{}{
+		"msg": "method", "method": "sendMessage", "id": t.nextID(),
+		"params": []interface{}{map[string]interface{}{"rid": msg.Channel, "msg": msg.Message}},
+	})
+}
+
+// Ack is a no-op: Rocket.Chat's realtime API has no envelope_id
+// acknowledgement concept.
+func (t *RocketChatTransport) Ack(id string) error {
+	return nil
+}
+
+// Close tears down the websocket connection.
+func (t *RocketChatTransport) Close() error {
+	if t.socket == nil {
+		return nil
+	}
+
+	return t.socket.Close()
+}
+
+// Self returns the bot's own Rocket.Chat user ID.
+func (t *RocketChatTransport) Self() string {
+	return t.self
+}
+
+// FormatMention renders userID as Rocket.Chat's "@username" mention
+// markup.
+func (t *RocketChatTransport) FormatMention(userID string) string {
+	return "@" + userID
+}
+
+// StripMarkup strips the first "@botID" mention out of incoming text,
+// wherever it appears.
+func (t *RocketChatTransport) StripMarkup(text, botID string) string {
+	return strings.TrimSpace(strings.Replace(text, t.FormatMention(botID), "", 1))
+}
+
+func (t *RocketChatTransport) send(v interface{}) error {
+	t.sendLock.Lock()
+	defer t.sendLock.Unlock()
+
+	err := websocket.JSON.Send(t.socket, v)
+	if err != nil && t.metrics != nil {
+		t.metrics.IncSendError()
+	}
+
+	return err
+}
+
+// nextID returns a unique DDP message ID. Send (and so Conversation.Reply)
+// runs from the per-invocation goroutine Bot.searchCommand/searchListener
+// spawn, so concurrent replies can race here; atomic.AddInt64 keeps the
+// increment safe without taking sendLock for the whole call.
+func (t *RocketChatTransport) nextID() string {
+	id := atomic.AddInt64(&t.msgID, 1)
+	return fmt.Sprintf("hanu-%d-%d", id, time.Now().UnixNano())
+}
@@ -0,0 +1,105 @@
+package hanu
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+// fakeDDPServer starts an httptest server speaking just enough DDP to
+// exercise dial(): it acks "connect" with "connected", answers the login
+// method with a "result" envelope carrying selfID, and answers
+// subscriptions/get with subs.
+func fakeDDPServer(t *testing.T, selfID string, subs []map[string]interface{}) *httptest.Server {
+	t.Helper()
+
+	return httptest.NewServer(websocket.Handler(func(ws *websocket.Conn) {
+		for {
+			var raw map[string]interface{}
+			if err := websocket.JSON.Receive(ws, &raw); err != nil {
+				return
+			}
+
+			switch {
+			case raw["msg"] == "connect":
+				websocket.JSON.Send(ws, map[string]interface{}{"msg": "connected", "session": "s1"})
+			case raw["msg"] == "method" && raw["method"] == "login":
+				websocket.JSON.Send(ws, map[string]interface{}{
+					"msg": "result", "id": raw["id"],
+					"result": map[string]interface{}{"id": selfID},
+				})
+			case raw["msg"] == "method" && raw["method"] == "subscriptions/get":
+				websocket.JSON.Send(ws, map[string]interface{}{
+					"msg": "result", "id": raw["id"], "result": subs,
+				})
+			}
+		}
+	}))
+}
+
+func TestRocketChatDialSetsSelfFromLoginResult(t *testing.T) {
+	server := fakeDDPServer(t, "U123", nil)
+	defer server.Close()
+
+	transport := NewRocketChatTransport("ws"+strings.TrimPrefix(server.URL, "http"), "bot", "secret")
+	if err := transport.dial(); err != nil {
+		t.Fatalf("dial failed: %s", err)
+	}
+	defer transport.Close()
+
+	if transport.self != "U123" {
+		t.Errorf("self = %q, want %q (connect ack must not be mistaken for the login result)", transport.self, "U123")
+	}
+}
+
+func TestRocketChatDialSeedsDirectRoomsFromSubscriptions(t *testing.T) {
+	server := fakeDDPServer(t, "U123", []map[string]interface{}{
+		{"rid": "dm1", "t": "d"},
+		{"rid": "chan1", "t": "c"},
+	})
+	defer server.Close()
+
+	transport := NewRocketChatTransport("ws"+strings.TrimPrefix(server.URL, "http"), "bot", "secret")
+	if err := transport.dial(); err != nil {
+		t.Fatalf("dial failed: %s", err)
+	}
+	defer transport.Close()
+
+	if !transport.directRooms["dm1"] {
+		t.Error("directRooms[dm1] = false, want true for a \"t\":\"d\" subscription")
+	}
+	if transport.directRooms["chan1"] {
+		t.Error("directRooms[chan1] = true, want false for a \"t\":\"c\" subscription")
+	}
+}
+
+func TestRocketChatDispatchMessageUsesDirectRoomsNotRIDPrefix(t *testing.T) {
+	transport := NewRocketChatTransport("", "bot", "secret")
+	transport.self = "U123"
+	transport.directRooms = map[string]bool{"U123xyz": false}
+
+	fields, err := json.Marshal(map[string]interface{}{
+		"args": []interface{}{map[string]interface{}{
+			"msg": "hi", "rid": "U123xyz",
+			"u": map[string]interface{}{"_id": "U999"},
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go transport.dispatchMessage(fields)
+
+	select {
+	case msg := <-transport.messages:
+		if msg.Direct {
+			t.Error("Direct = true for a channel room whose ID happens to start with self's ID; the RID-prefix heuristic should not be used")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("dispatchMessage did not deliver a message")
+	}
+}
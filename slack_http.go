@@ -0,0 +1,251 @@
+package hanu
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const slackSignatureReplayWindow = 5 * time.Minute
+
+// ServeHTTP implements HTTPTransport, handling Slack slash commands,
+// interactive components (button clicks, select menus, modal
+// submissions) and Events API callbacks. Every request is verified
+// against SigningSecret before anything in its body is trusted.
+func (t *SlackTransport) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "failed to read body", http.StatusBadRequest)
+		return
+	}
+
+	if err := t.verifySignature(r, body); err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		t.handleEventsAPI(w, body)
+		return
+	}
+
+	t.handleFormPost(w, body)
+}
+
+// verifySignature checks X-Slack-Signature against an HMAC-SHA256 of the
+// request timestamp and raw body, keyed with SigningSecret, and rejects
+// anything older than slackSignatureReplayWindow to block replays.
+func (t *SlackTransport) verifySignature(r *http.Request, body []byte) error {
+	ts := r.Header.Get("X-Slack-Request-Timestamp")
+	sig := r.Header.Get("X-Slack-Signature")
+	if ts == "" || sig == "" {
+		return errors.New("missing signature headers")
+	}
+
+	seconds, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return errors.New("invalid request timestamp")
+	}
+
+	age := time.Since(time.Unix(seconds, 0))
+	if age > slackSignatureReplayWindow || age < -slackSignatureReplayWindow {
+		return errors.New("request timestamp outside replay window")
+	}
+
+	mac := hmac.New(sha256.New, []byte(t.SigningSecret))
+	mac.Write([]byte("v0:" + ts + ":"))
+	mac.Write(body)
+	expected := "v0=" + hex.EncodeToString(mac.Sum(nil))
+
+	if !hmac.Equal([]byte(expected), []byte(sig)) {
+		return errors.New("signature mismatch")
+	}
+
+	return nil
+}
+
+// handleEventsAPI answers Slack's url_verification handshake directly
+// and otherwise unwraps an event_callback into a Message for the usual
+// Commands/Listeners pipeline.
+func (t *SlackTransport) handleEventsAPI(w http.ResponseWriter, body []byte) {
+	var payload struct {
+		Type      string          `json:"type"`
+		Challenge string          `json:"challenge"`
+		Event     json.RawMessage `json:"event"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		http.Error(w, "invalid payload", http.StatusBadRequest)
+		return
+	}
+
+	if payload.Type == "url_verification" {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte(payload.Challenge))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+
+	var event struct {
+		Text        string `json:"text"`
+		User        string `json:"user"`
+		Channel     string `json:"channel"`
+		ChannelType string `json:"channel_type"`
+	}
+	if err := json.Unmarshal(payload.Event, &event); err != nil {
+		log.Printf("Failed to unmarshal events_api event: %s\n", err)
+		return
+	}
+
+	if event.User == t.self {
+		return
+	}
+
+	t.messages <- Message{
+		Message: event.Text,
+		UserID:  event.User,
+		Channel: event.Channel,
+		Direct:  event.ChannelType == "im",
+	}
+}
+
+// handleFormPost handles the application/x-www-form-urlencoded bodies
+// Slack sends for slash commands, and the "payload" JSON field it sends
+// for interactive components.
+func (t *SlackTransport) handleFormPost(w http.ResponseWriter, body []byte) {
+	values, err := url.ParseQuery(string(body))
+	if err != nil {
+		http.Error(w, "invalid form body", http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+
+	if payload := values.Get("payload"); payload != "" {
+		t.handleInteractive(payload)
+		return
+	}
+
+	command := strings.TrimPrefix(values.Get("command"), "/")
+
+	t.messages <- Message{
+		Message:   strings.TrimSpace(command + " " + values.Get("text")),
+		UserID:    values.Get("user_id"),
+		Channel:   values.Get("channel_id"),
+		TriggerID: values.Get("trigger_id"),
+		Addressed: true,
+	}
+}
+
+// handleInteractive decodes a block_actions/view_submission payload
+// into a Message: its text is "<action_id> <value>" for a button or
+// select, or just its type for anything else (e.g. a modal submission).
+// The Message carries only that text, the user, channel and trigger_id;
+// the rest of the payload, such as a submitted view's state, is dropped.
+func (t *SlackTransport) handleInteractive(payload string) {
+	var interaction struct {
+		Type      string `json:"type"`
+		TriggerID string `json:"trigger_id"`
+		User      struct {
+			ID string `json:"id"`
+		} `json:"user"`
+		Channel struct {
+			ID string `json:"id"`
+		} `json:"channel"`
+		Actions []struct {
+			ActionID string `json:"action_id"`
+			Value    string `json:"value"`
+		} `json:"actions"`
+	}
+	if err := json.Unmarshal([]byte(payload), &interaction); err != nil {
+		log.Printf("Failed to unmarshal interactive payload: %s\n", err)
+		return
+	}
+
+	text := interaction.Type
+	if len(interaction.Actions) > 0 {
+		text = strings.TrimSpace(interaction.Actions[0].ActionID + " " + interaction.Actions[0].Value)
+	}
+
+	t.messages <- Message{
+		Message:   text,
+		UserID:    interaction.User.ID,
+		Channel:   interaction.Channel.ID,
+		TriggerID: interaction.TriggerID,
+		Addressed: true,
+	}
+}
+
+// SendBlocks posts a rich chat.postMessage with the given blocks to
+// msg's channel, implementing Interactive.
+func (t *SlackTransport) SendBlocks(msg Message, blocks interface{}) error {
+	return t.callWebAPI("chat.postMessage", map[string]interface{}{
+		"channel": msg.Channel,
+		"blocks":  blocks,
+	})
+}
+
+// OpenModal opens view via views.open, implementing Interactive.
+func (t *SlackTransport) OpenModal(triggerID string, view interface{}) error {
+	return t.callWebAPI("views.open", map[string]interface{}{
+		"trigger_id": triggerID,
+		"view":       view,
+	})
+}
+
+// UpdateModal updates view via views.update, implementing Interactive.
+func (t *SlackTransport) UpdateModal(viewID string, view interface{}) error {
+	return t.callWebAPI("views.update", map[string]interface{}{
+		"view_id": viewID,
+		"view":    view,
+	})
+}
+
+func (t *SlackTransport) callWebAPI(method string, params map[string]interface{}) error {
+	base := t.apiBaseURL
+	if base == "" {
+		base = "https://slack.com/api"
+	}
+
+	body, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", base+"/"+method, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+t.Token)
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	var result struct {
+		Ok    bool   `json:"ok"`
+		Error string `json:"error"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return err
+	}
+
+	if !result.Ok {
+		return errors.New(result.Error)
+	}
+
+	return nil
+}
@@ -0,0 +1,130 @@
+package hanu
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func signedRequest(secret, ts string, body []byte) *http.Request {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("v0:" + ts + ":"))
+	mac.Write(body)
+	sig := "v0=" + hex.EncodeToString(mac.Sum(nil))
+
+	r := httptest.NewRequest("POST", "/", nil)
+	r.Header.Set("X-Slack-Request-Timestamp", ts)
+	r.Header.Set("X-Slack-Signature", sig)
+
+	return r
+}
+
+func TestVerifySignatureValid(t *testing.T) {
+	transport := &SlackTransport{SigningSecret: "secret"}
+	body := []byte(`{"type":"event_callback"}`)
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+
+	if err := transport.verifySignature(signedRequest("secret", ts, body), body); err != nil {
+		t.Errorf("verifySignature failed for a validly signed request: %s", err)
+	}
+}
+
+func TestVerifySignatureWrongSecret(t *testing.T) {
+	transport := &SlackTransport{SigningSecret: "secret"}
+	body := []byte(`{"type":"event_callback"}`)
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+
+	if err := transport.verifySignature(signedRequest("wrong", ts, body), body); err == nil {
+		t.Error("verifySignature accepted a request signed with the wrong secret")
+	}
+}
+
+func TestVerifySignatureTamperedBody(t *testing.T) {
+	transport := &SlackTransport{SigningSecret: "secret"}
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	r := signedRequest("secret", ts, []byte(`{"type":"event_callback"}`))
+
+	if err := transport.verifySignature(r, []byte(`{"type":"tampered"}`)); err == nil {
+		t.Error("verifySignature accepted a body that doesn't match the signature")
+	}
+}
+
+func TestVerifySignatureReplay(t *testing.T) {
+	transport := &SlackTransport{SigningSecret: "secret"}
+	body := []byte(`{"type":"event_callback"}`)
+	old := strconv.FormatInt(time.Now().Add(-slackSignatureReplayWindow*2).Unix(), 10)
+
+	if err := transport.verifySignature(signedRequest("secret", old, body), body); err == nil {
+		t.Error("verifySignature accepted a timestamp outside the replay window")
+	}
+}
+
+func TestVerifySignatureMissingHeaders(t *testing.T) {
+	transport := &SlackTransport{SigningSecret: "secret"}
+	r := httptest.NewRequest("POST", "/", nil)
+
+	if err := transport.verifySignature(r, nil); err == nil {
+		t.Error("verifySignature accepted a request with no signature headers")
+	}
+}
+
+func formRequest(secret string, form url.Values) *http.Request {
+	body := []byte(form.Encode())
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+
+	r := signedRequest(secret, ts, body)
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	return r
+}
+
+// TestServeHTTPSlashCommandRoutesToCommandWithArgs exercises the full
+// ServeHTTP -> handleFormPost -> Bot.process path: Slack sends the slash
+// command name with its leading "/", which must be stripped before it
+// reaches a CommandWithArgs's "^name..." pattern or the handler never
+// fires.
+func TestServeHTTPSlashCommandRoutesToCommandWithArgs(t *testing.T) {
+	transport := &SlackTransport{SigningSecret: "secret", messages: make(chan Message, 1)}
+	bot := &Bot{Transport: transport, Prefix: "!"}
+	got := make(chan string, 1)
+	bot.CommandWithArgs("deploy", "deploys an environment", []Arg{
+		{Name: "env", Type: ArgChoice, Choices: []string{"staging", "prod"}},
+	}, func(conv ConversationInterface, args Args) {
+		got <- args["env"]
+	})
+
+	form := url.Values{
+		"command":    {"/deploy"},
+		"text":       {"staging"},
+		"user_id":    {"U1"},
+		"channel_id": {"C1"},
+	}
+
+	w := httptest.NewRecorder()
+	transport.ServeHTTP(w, formRequest("secret", form))
+
+	select {
+	case msg := <-transport.messages:
+		bot.process(msg)
+	case <-time.After(time.Second):
+		t.Fatal("ServeHTTP did not deliver a message for the slash command")
+	}
+
+	select {
+	case env := <-got:
+		if env != "staging" {
+			t.Errorf("handler got env=%q, want %q", env, "staging")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("CommandWithArgs handler was never invoked; leading \"/\" was likely left on the command name")
+	}
+}
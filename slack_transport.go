@@ -0,0 +1,400 @@
+package hanu
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+const (
+	minReconnectBackoff = 1 * time.Second
+	maxReconnectBackoff = 30 * time.Second
+	keepaliveInterval   = 30 * time.Second
+)
+
+var slackMentionPattern = regexp.MustCompile(`<@([A-Za-z0-9]+)>`)
+var slackLinkPattern = regexp.MustCompile(`<([^|>]+)\|([^>]+)>`)
+
+// SlackTransport speaks Slack's Socket Mode protocol: it opens a
+// websocket via apps.connections.open (authenticated with an app-level
+// token), reconnects with exponential backoff + jitter when the socket
+// drops, and acknowledges every envelope that requires it.
+type SlackTransport struct {
+	Token         string
+	AppToken      string
+	SigningSecret string // required for ListenHTTP; see slack_http.go
+
+	socket   *websocket.Conn
+	self     string
+	messages chan Message
+
+	sendLock sync.Mutex
+
+	// apiBaseURL overrides https://slack.com/api for tests; empty means
+	// the real Web API.
+	apiBaseURL string
+
+	logger  Logger
+	metrics Metrics
+}
+
+// SetLogger replaces the transport's logger, used for Socket Mode
+// connection lifecycle events (reconnects, dropped envelope types).
+// Bots usually set this via bot.SetLogger instead of calling it
+// directly.
+func (t *SlackTransport) SetLogger(l Logger) {
+	t.logger = l
+}
+
+// SetMetrics replaces the transport's metrics sink, used to report
+// reconnects and Send errors. Bots usually set this via bot.SetMetrics
+// instead of calling it directly.
+func (t *SlackTransport) SetMetrics(m Metrics) {
+	t.metrics = m
+}
+
+func (t *SlackTransport) log() Logger {
+	if t.logger == nil {
+		return DefaultLogger
+	}
+
+	return t.logger
+}
+
+// NewSlackTransport creates a Transport for a Slack bot over Socket
+// Mode. token is a bot token (xoxb-...); appToken is an app-level token
+// (xapp-...) with the connections:write scope.
+func NewSlackTransport(token, appToken string) *SlackTransport {
+	return &SlackTransport{
+		Token:    token,
+		AppToken: appToken,
+		messages: make(chan Message),
+	}
+}
+
+// NewSlack is a convenience wrapper for the common case of a Slack bot.
+func NewSlack(token, appToken string) (*Bot, error) {
+	return New(NewSlackTransport(token, appToken))
+}
+
+// Connect opens the Socket Mode websocket, resolves the bot's own user
+// ID and starts the read/reconnect loop in the background.
+func (t *SlackTransport) Connect() error {
+	if err := t.dial(); err != nil {
+		return err
+	}
+
+	go t.listen()
+
+	return nil
+}
+
+func (t *SlackTransport) dial() error {
+	url, err := t.openConnection()
+	if err != nil {
+		return err
+	}
+
+	socket, err := websocket.Dial(url, "", "https://api.slack.com/")
+	if err != nil {
+		return err
+	}
+	t.sendLock.Lock()
+	t.socket = socket
+	t.sendLock.Unlock()
+
+	if t.self == "" {
+		id, err := t.resolveSelf()
+		if err != nil {
+			return err
+		}
+		t.self = id
+	}
+
+	return nil
+}
+
+// listen reads envelopes off the current socket, reconnecting with
+// backoff whenever it drops. A failed dial is logged and retried after
+// the next backoff; it never gives up.
+func (t *SlackTransport) listen() {
+	var backoff time.Duration
+
+	for {
+		done := make(chan struct{})
+		go t.keepalive(done)
+
+		t.readEnvelopes(&backoff)
+		close(done)
+
+		backoff = nextBackoff(backoff)
+		t.log().Infof("Slack Socket Mode connection lost, reconnecting in %s", backoff)
+		time.Sleep(backoff)
+
+		if err := t.dial(); err != nil {
+			t.log().Errorf("Slack reconnect failed: %s", err)
+		} else if t.metrics != nil {
+			t.metrics.IncReconnect()
+		}
+	}
+}
+
+func (t *SlackTransport) readEnvelopes(backoff *time.Duration) {
+	for {
+		var env envelope
+		if err := websocket.JSON.Receive(t.socket, &env); err != nil {
+			return
+		}
+
+		switch env.Type {
+		case "hello":
+			*backoff = 0
+		case "events_api":
+			t.Ack(env.EnvelopeID)
+			t.dispatchEvent(env.Payload)
+		case "slash_commands", "interactive":
+			// Full handling arrives over HTTP mode; for now just
+			// acknowledge so Slack doesn't retry the envelope.
+			t.Ack(env.EnvelopeID)
+			t.log().Infof("%s envelopes are not yet handled over Socket Mode", env.Type)
+		case "disconnect":
+			return
+		}
+	}
+}
+
+func (t *SlackTransport) dispatchEvent(payload json.RawMessage) {
+	var wrapper struct {
+		Event struct {
+			Text        string `json:"text"`
+			User        string `json:"user"`
+			Channel     string `json:"channel"`
+			ChannelType string `json:"channel_type"`
+		} `json:"event"`
+	}
+	if err := json.Unmarshal(payload, &wrapper); err != nil {
+		t.log().Errorf("Failed to unmarshal events_api payload: %s", err)
+		return
+	}
+
+	if wrapper.Event.User == t.self {
+		return
+	}
+
+	t.messages <- Message{
+		Message: wrapper.Event.Text,
+		UserID:  wrapper.Event.User,
+		Channel: wrapper.Event.Channel,
+		Direct:  wrapper.Event.ChannelType == "im",
+	}
+}
+
+// Receive returns the channel incoming Messages are delivered on.
+func (t *SlackTransport) Receive() <-chan Message {
+	return t.messages
+}
+
+// Send posts msg via chat.postMessage on the Web API. Socket Mode's
+// websocket is receive-only (events plus envelope acks); there is no
+// way to push an arbitrary outbound message over it, unlike the classic
+// rtm.start socket this replaced.
+func (t *SlackTransport) Send(msg Message) error {
+	err := t.callWebAPI("chat.postMessage", map[string]interface{}{
+		"channel": msg.Channel,
+		"text":    msg.Message,
+	})
+	if err != nil && t.metrics != nil {
+		t.metrics.IncSendError()
+	}
+
+	return err
+}
+
+// Ack sends the envelope_id acknowledgement Slack requires for every
+// slash_commands, interactive and events_api envelope within 3 seconds,
+// or it will be retried.
+func (t *SlackTransport) Ack(id string) error {
+	if id == "" {
+		return nil
+	}
+
+	t.sendLock.Lock()
+	defer t.sendLock.Unlock()
+
+	return websocket.JSON.Send(t.socket, envelopeAck{EnvelopeID: id})
+}
+
+// Close tears down the websocket connection.
+func (t *SlackTransport) Close() error {
+	if t.socket == nil {
+		return nil
+	}
+
+	return t.socket.Close()
+}
+
+// Self returns the bot's own Slack user ID.
+func (t *SlackTransport) Self() string {
+	return t.self
+}
+
+// FormatMention renders userID as Slack's "<@U123>" mention markup.
+func (t *SlackTransport) FormatMention(userID string) string {
+	return "<@" + userID + ">"
+}
+
+// StripMarkup strips Slack's own mention ("<@U123>") and link
+// ("<https://x|label>") markup out of incoming text.
+func (t *SlackTransport) StripMarkup(text, botID string) string {
+	text = strings.Replace(text, t.FormatMention(botID), "", 1)
+	text = slackMentionPattern.ReplaceAllString(text, "")
+	text = slackLinkPattern.ReplaceAllString(text, "$2")
+
+	return strings.TrimSpace(text)
+}
+
+// keepalive periodically pings the socket so Slack (and any proxies in
+// between) know the connection is still alive, similar to nlopes/slack's
+// ping loop for the classic RTM websocket.
+func (t *SlackTransport) keepalive(done <-chan struct{}) {
+	ticker := time.NewTicker(keepaliveInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			t.sendLock.Lock()
+			err := websocket.JSON.Send(t.socket, envelope{Type: "ping"})
+			t.sendLock.Unlock()
+			if err != nil {
+				return
+			}
+		case <-done:
+			return
+		}
+	}
+}
+
+// envelope is the generic Socket Mode message wrapper Slack sends over
+// the websocket. The Type field tells us how to decode Payload.
+// See https://api.slack.com/apis/connections/socket
+type envelope struct {
+	Type                   string          `json:"type"`
+	EnvelopeID             string          `json:"envelope_id,omitempty"`
+	Payload                json.RawMessage `json:"payload,omitempty"`
+	AcceptsResponsePayload bool            `json:"accepts_response_payload,omitempty"`
+}
+
+// envelopeAck is sent back over the socket to acknowledge receipt of an
+// envelope that carries an envelope_id (slash_commands, interactive,
+// events_api). Slack resends unacknowledged envelopes otherwise.
+type envelopeAck struct {
+	EnvelopeID string `json:"envelope_id"`
+}
+
+type connectionsOpenResponse struct {
+	Ok    bool   `json:"ok"`
+	Error string `json:"error"`
+	URL   string `json:"url"`
+}
+
+type authTestResponse struct {
+	Ok     bool   `json:"ok"`
+	Error  string `json:"error"`
+	UserID string `json:"user_id"`
+}
+
+// openConnection requests a fresh, single-use Socket Mode websocket URL
+// via apps.connections.open, authenticated with the app-level token
+// (xapp-...).
+func (t *SlackTransport) openConnection() (string, error) {
+	req, err := http.NewRequest("POST", "https://slack.com/api/apps.connections.open", nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+t.AppToken)
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", errors.New("Failed to connect to Slack apps.connections.open API")
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != 200 {
+		return "", fmt.Errorf("Failed with HTTP Code: %d", res.StatusCode)
+	}
+
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return "", fmt.Errorf("Failed to read body from response")
+	}
+
+	var response connectionsOpenResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return "", fmt.Errorf("Failed to unmarshal JSON: %s", body)
+	}
+
+	if !response.Ok {
+		return "", errors.New(response.Error)
+	}
+
+	return response.URL, nil
+}
+
+// resolveSelf fetches the bot's own user ID via auth.test, using the bot
+// token. Socket Mode's handshake doesn't hand this back to us, unlike
+// the old rtm.start response.
+func (t *SlackTransport) resolveSelf() (string, error) {
+	req, err := http.NewRequest("POST", "https://slack.com/api/auth.test", nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+t.Token)
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", errors.New("Failed to connect to Slack auth.test API")
+	}
+	defer res.Body.Close()
+
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return "", fmt.Errorf("Failed to read body from response")
+	}
+
+	var response authTestResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return "", fmt.Errorf("Failed to unmarshal JSON: %s", body)
+	}
+
+	if !response.Ok {
+		return "", errors.New(response.Error)
+	}
+
+	return response.UserID, nil
+}
+
+// nextBackoff doubles prev (starting at minReconnectBackoff), caps it at
+// maxReconnectBackoff and adds up to 20% jitter so a fleet of bots
+// doesn't hammer Slack in lockstep after a shared outage.
+func nextBackoff(prev time.Duration) time.Duration {
+	next := prev * 2
+	if next < minReconnectBackoff {
+		next = minReconnectBackoff
+	}
+	if next > maxReconnectBackoff {
+		next = maxReconnectBackoff
+	}
+
+	jitter := time.Duration(rand.Int63n(int64(next) / 5))
+	return next + jitter
+}
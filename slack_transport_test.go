@@ -0,0 +1,95 @@
+package hanu
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// countingMetrics is a minimal Metrics stub for tests that only care
+// about send errors.
+type countingMetrics struct {
+	sendErrors int
+}
+
+func (m *countingMetrics) IncInvocation(name string)                   {}
+func (m *countingMetrics) ObserveLatency(name string, d time.Duration) {}
+func (m *countingMetrics) IncMatchFailure(kind string)                 {}
+func (m *countingMetrics) IncReconnect()                               {}
+func (m *countingMetrics) IncSendError()                               { m.sendErrors++ }
+
+func TestNextBackoff(t *testing.T) {
+	prev := time.Duration(0)
+
+	for i := 0; i < 10; i++ {
+		next := nextBackoff(prev)
+
+		if next < minReconnectBackoff {
+			t.Fatalf("nextBackoff(%s) = %s, want >= %s", prev, next, minReconnectBackoff)
+		}
+		if next > maxReconnectBackoff+maxReconnectBackoff/5 {
+			t.Fatalf("nextBackoff(%s) = %s, want <= %s plus jitter", prev, next, maxReconnectBackoff)
+		}
+
+		prev = next
+	}
+
+	if got := nextBackoff(maxReconnectBackoff); got < maxReconnectBackoff {
+		t.Errorf("nextBackoff(max) = %s, want capped at >= %s", got, maxReconnectBackoff)
+	}
+}
+
+// TestSendPostsChatPostMessage asserts Send calls chat.postMessage over
+// the Web API instead of writing the Message onto the (receive-only)
+// Socket Mode websocket.
+func TestSendPostsChatPostMessage(t *testing.T) {
+	var gotMethod, gotAuth string
+	var gotBody struct {
+		Channel string `json:"channel"`
+		Text    string `json:"text"`
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotMethod = r.URL.Path
+		gotAuth = r.Header.Get("Authorization")
+		json.NewDecoder(r.Body).Decode(&gotBody)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer server.Close()
+
+	transport := &SlackTransport{Token: "xoxb-test", apiBaseURL: server.URL}
+	if err := transport.Send(Message{Channel: "C1", Message: "hello"}); err != nil {
+		t.Fatalf("Send failed: %s", err)
+	}
+
+	if gotMethod != "/chat.postMessage" {
+		t.Errorf("Send hit %q, want /chat.postMessage", gotMethod)
+	}
+	if gotAuth != "Bearer xoxb-test" {
+		t.Errorf("Send sent Authorization=%q, want Bearer xoxb-test", gotAuth)
+	}
+	if gotBody.Channel != "C1" || gotBody.Text != "hello" {
+		t.Errorf("Send posted %+v, want channel=C1 text=hello", gotBody)
+	}
+}
+
+func TestSendReportsWebAPIErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ok":false,"error":"channel_not_found"}`))
+	}))
+	defer server.Close()
+
+	var metrics countingMetrics
+	transport := &SlackTransport{Token: "xoxb-test", apiBaseURL: server.URL, metrics: &metrics}
+	if err := transport.Send(Message{Channel: "bad", Message: "hi"}); err == nil {
+		t.Error("Send did not return an error for a non-ok chat.postMessage response")
+	}
+	if metrics.sendErrors != 1 {
+		t.Errorf("metrics.sendErrors = %d, want 1", metrics.sendErrors)
+	}
+}
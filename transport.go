@@ -0,0 +1,61 @@
+package hanu
+
+import "net/http"
+
+// Transport decouples Bot from any one chat backend. Implementations
+// live alongside the concrete chat systems they speak to (see
+// slack_transport.go, rocketchat_transport.go, irc_transport.go).
+type Transport interface {
+	// Connect establishes the underlying connection, authenticating as
+	// needed. It must populate whatever Self() later returns.
+	Connect() error
+
+	// Receive returns the channel incoming Messages are delivered on.
+	// It is closed when the transport gives up reconnecting.
+	Receive() <-chan Message
+
+	// Send delivers a Message back to the chat system.
+	Send(msg Message) error
+
+	// Ack acknowledges a message that required it (e.g. a Slack Socket
+	// Mode envelope_id). Transports that don't need this make it a
+	// no-op.
+	Ack(id string) error
+
+	// Close tears down the connection.
+	Close() error
+
+	// Self returns the bot's own user ID, as assigned by the chat
+	// system.
+	Self() string
+
+	// FormatMention renders a mention of userID using this transport's
+	// native markup (e.g. Slack's "<@U123>").
+	FormatMention(userID string) string
+
+	// StripMarkup removes this transport's native formatting (mentions,
+	// link wrappers, …) from an incoming message's raw text.
+	StripMarkup(text, botID string) string
+}
+
+// HTTPTransport is implemented by Transports that can also be driven
+// over HTTP (slash commands, interactive components, Events API
+// callbacks), as an alternative or addition to a persistent socket. See
+// Bot.ListenHTTP.
+type HTTPTransport interface {
+	Transport
+	http.Handler
+}
+
+// Interactive is implemented by Transports that can render rich,
+// interactive UI beyond plain text replies: attachments/blocks and
+// modals.
+type Interactive interface {
+	// SendBlocks posts a rich message to msg's channel.
+	SendBlocks(msg Message, blocks interface{}) error
+	// OpenModal opens a new modal, using the trigger_id captured from
+	// the interaction that's prompting it.
+	OpenModal(triggerID string, view interface{}) error
+	// UpdateModal updates an already-open modal by its view ID.
+	UpdateModal(viewID string, view interface{}) error
+}